@@ -130,6 +130,20 @@ func callMCPRaw(serverURL, queryString, method string, params any) (map[string]a
 	return result, resp.StatusCode, nil
 }
 
+// errorCode extracts the taxonomy code from a JSON-RPC error's data field
+func errorCode(resp map[string]any) string {
+	errObj, ok := resp["error"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	data, ok := errObj["data"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	code, _ := data["code"].(string)
+	return code
+}
+
 func countTools(resp map[string]any) int {
 	if resp["error"] != nil {
 		return -1
@@ -302,14 +316,10 @@ func testToolCapabilityEnforcement() error {
 		}
 	}
 
-	// Error is expected - verify it's permission related, not just a timeout
-	errObj := resp["error"].(map[string]any)
-	errMsg := errObj["message"].(string)
-
-	// The error should mention the tool is not found (for this user) or permission denied
-	// NOT a timeout error (which would mean we tried to execute it)
-	if strings.Contains(errMsg, "timeout") || strings.Contains(errMsg, "deadline") {
-		return fmt.Errorf("SECURITY: tool execution was attempted for unauthorized tool (got: %s)", errMsg)
+	// Error is expected - verify it's a permission denial, not a timeout or
+	// routing failure (which would mean we tried to execute it)
+	if code := errorCode(resp); code != "PERMISSION_DENIED" {
+		return fmt.Errorf("SECURITY: expected PERMISSION_DENIED for unauthorized tool, got code %q (error: %v)", code, resp["error"])
 	}
 
 	return nil
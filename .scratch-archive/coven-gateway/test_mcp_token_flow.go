@@ -151,6 +151,20 @@ func callMCP(serverURL, token, method string, params any) (map[string]any, error
 	return result, nil
 }
 
+// errorCode extracts the taxonomy code from a JSON-RPC error's data field
+func errorCode(resp map[string]any) string {
+	errObj, ok := resp["error"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	data, ok := errObj["data"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	code, _ := data["code"].(string)
+	return code
+}
+
 // Scenario 1: Token creation filters tools by capability
 func testTokenCreationAndFiltering() error {
 	server, tokenStore, _ := setupTestServer()
@@ -385,6 +399,9 @@ func testToolsCallRouting() error {
 	if resp["error"] == nil {
 		return fmt.Errorf("expected error when calling tool without pack")
 	}
+	if code := errorCode(resp); code != "PACK_UNAVAILABLE" {
+		return fmt.Errorf("expected PACK_UNAVAILABLE for tool without pack, got %q", code)
+	}
 
 	// Try to call a tool we don't have permission for
 	resp, err = callMCP(server.URL, token, "tools/call", map[string]any{
@@ -395,10 +412,25 @@ func testToolsCallRouting() error {
 		return err
 	}
 
-	// Should get a permission error or tool not found
+	// Should get a permission error, not a routing error
 	if resp["error"] == nil {
 		return fmt.Errorf("expected error when calling tool without permission")
 	}
+	if code := errorCode(resp); code != "PERMISSION_DENIED" {
+		return fmt.Errorf("expected PERMISSION_DENIED for tool without permission, got %q", code)
+	}
+
+	// Unknown tools are distinguished from tools the token can't see
+	resp, err = callMCP(server.URL, token, "tools/call", map[string]any{
+		"name":      "no-such-tool",
+		"arguments": map[string]any{},
+	})
+	if err != nil {
+		return err
+	}
+	if code := errorCode(resp); code != "TOOL_NOT_FOUND" {
+		return fmt.Errorf("expected TOOL_NOT_FOUND for unknown tool, got %q", code)
+	}
 
 	return nil
 }
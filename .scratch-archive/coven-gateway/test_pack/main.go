@@ -1,16 +1,22 @@
 // ABOUTME: Simple test pack that provides an "echo" tool for end-to-end testing
-// ABOUTME: Connects to gateway via gRPC and handles tool execution requests
+// ABOUTME: plus failure/latency injection tools for gateway resilience scenarios
 
 package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	pb "github.com/2389/coven-gateway/proto/coven"
 	"google.golang.org/grpc"
@@ -62,17 +68,78 @@ func main() {
 				RequiredCapabilities: []string{"admin"},
 				TimeoutSeconds:       30,
 			},
+			{
+				Name:        "fail",
+				Description: "Always fails with a structured error - for testing error paths",
+				InputSchemaJson: `{
+					"type": "object",
+					"properties": {
+						"code": {"type": "string", "description": "Error code to return (default INJECTED_FAILURE)"},
+						"message": {"type": "string", "description": "Error message to return"}
+					}
+				}`,
+				TimeoutSeconds: 30,
+			},
+			{
+				Name:        "slow",
+				Description: "Sleeps for the given number of milliseconds before responding",
+				InputSchemaJson: `{
+					"type": "object",
+					"properties": {
+						"ms": {"type": "integer", "minimum": 0, "description": "Milliseconds to sleep"}
+					},
+					"required": ["ms"]
+				}`,
+				TimeoutSeconds: 120,
+			},
+			{
+				Name:        "flaky",
+				Description: "Fails the given percentage of calls, echoes otherwise",
+				InputSchemaJson: `{
+					"type": "object",
+					"properties": {
+						"failure_percent": {"type": "integer", "minimum": 0, "maximum": 100, "description": "Chance of failure (default 50)"},
+						"message": {"type": "string"}
+					}
+				}`,
+				TimeoutSeconds: 30,
+			},
+			{
+				Name:        "huge_output",
+				Description: "Returns a payload of the given size in KB",
+				InputSchemaJson: `{
+					"type": "object",
+					"properties": {
+						"kb": {"type": "integer", "minimum": 0, "maximum": 4000, "description": "Output size in kilobytes"}
+					},
+					"required": ["kb"]
+				}`,
+				TimeoutSeconds: 60,
+			},
 		},
 	}
 
+	// Fault injection knobs for resilience scenarios
+	dropAfter := envInt("DROP_AFTER_REQUESTS")
+	resultDelay := time.Duration(envInt("RESULT_DELAY_MS")) * time.Millisecond
+
 	log.Printf("Registering pack '%s' with %d tools...", manifest.PackId, len(manifest.Tools))
 
-	stream, err := client.Register(context.Background(), manifest)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Register(ctx, manifest)
 	if err != nil {
 		log.Fatalf("Failed to register: %v", err)
 	}
 
 	log.Println("Pack registered! Waiting for tool execution requests...")
+	if dropAfter > 0 {
+		log.Printf("  Will drop connection after %d requests (DROP_AFTER_REQUESTS)", dropAfter)
+	}
+	if resultDelay > 0 {
+		log.Printf("  Delaying every result by %s (RESULT_DELAY_MS)", resultDelay)
+	}
 
 	// Handle graceful shutdown
 	sigCh := make(chan os.Signal, 1)
@@ -84,7 +151,9 @@ func main() {
 		os.Exit(0)
 	}()
 
-	// Process tool execution requests
+	// Process tool execution requests; each runs in its own goroutine so a
+	// slow tool doesn't block the ones behind it
+	var handled atomic.Int64
 	for {
 		req, err := stream.Recv()
 		if err == io.EOF {
@@ -92,32 +161,128 @@ func main() {
 			return
 		}
 		if err != nil {
+			if ctx.Err() != nil {
+				log.Println("Connection dropped")
+				return
+			}
 			log.Fatalf("Error receiving: %v", err)
 		}
 
 		log.Printf("Received tool request: %s (id=%s)", req.ToolName, req.RequestId)
 		log.Printf("  Input: %s", req.InputJson)
 
-		// Execute the tool
-		var result string
-		switch req.ToolName {
-		case "echo", "admin_echo":
-			result = fmt.Sprintf(`{"echoed": %s, "tool": "%s"}`, req.InputJson, req.ToolName)
-		default:
-			result = fmt.Sprintf(`{"error": "unknown tool: %s"}`, req.ToolName)
+		go func(req *pb.ExecuteToolRequest) {
+			resp := executeTool(req)
+			if resultDelay > 0 {
+				time.Sleep(resultDelay)
+			}
+
+			_, err := client.ToolResult(context.Background(), resp)
+			if err != nil {
+				log.Printf("Failed to send result: %v", err)
+			} else {
+				log.Printf("Result sent for request %s", req.RequestId)
+			}
+
+			if n := handled.Add(1); dropAfter > 0 && n == int64(dropAfter) {
+				log.Printf("Dropping connection after %d requests", n)
+				cancel()
+			}
+		}(req)
+	}
+}
+
+// maxHugeOutputKB keeps huge_output results under grpc-go's default 4 MB
+// receive limit on the gateway's ToolResult RPC
+const maxHugeOutputKB = 4000
+
+// executeTool runs a single tool request and builds the response to send back
+func executeTool(req *pb.ExecuteToolRequest) *pb.ExecuteToolResponse {
+	// Echo tools pass arbitrary input through untouched
+	if req.ToolName == "echo" || req.ToolName == "admin_echo" {
+		return toolOutput(req.RequestId, fmt.Sprintf(`{"echoed": %s, "tool": "%s"}`, req.InputJson, req.ToolName))
+	}
+
+	var input struct {
+		Message        string `json:"message"`
+		Code           string `json:"code"`
+		Ms             int    `json:"ms"`
+		FailurePercent *int   `json:"failure_percent"`
+		KB             int    `json:"kb"`
+	}
+	if req.InputJson != "" {
+		if err := json.Unmarshal([]byte(req.InputJson), &input); err != nil {
+			return toolError(req.RequestId, "INVALID_ARGUMENTS", fmt.Sprintf("invalid input: %v", err))
 		}
+	}
 
-		// Send result back
-		resp := &pb.ExecuteToolResponse{
-			RequestId: req.RequestId,
-			Result:    &pb.ExecuteToolResponse_OutputJson{OutputJson: result},
+	switch req.ToolName {
+	case "fail":
+		code := input.Code
+		if code == "" {
+			code = "INJECTED_FAILURE"
+		}
+		message := input.Message
+		if message == "" {
+			message = "injected failure"
 		}
+		return toolError(req.RequestId, code, message)
 
-		_, err = client.ToolResult(context.Background(), resp)
-		if err != nil {
-			log.Printf("Failed to send result: %v", err)
-		} else {
-			log.Printf("Result sent for request %s", req.RequestId)
+	case "slow":
+		if input.Ms < 0 {
+			return toolError(req.RequestId, "INVALID_ARGUMENTS", "ms must not be negative")
 		}
+		time.Sleep(time.Duration(input.Ms) * time.Millisecond)
+		return toolOutput(req.RequestId, fmt.Sprintf(`{"slept_ms": %d}`, input.Ms))
+
+	case "flaky":
+		percent := 50
+		if input.FailurePercent != nil {
+			percent = *input.FailurePercent
+		}
+		if rand.Intn(100) < percent {
+			return toolError(req.RequestId, "FLAKY_FAILURE", fmt.Sprintf("flaky failure (%d%% failure rate)", percent))
+		}
+		outJSON, _ := json.Marshal(map[string]string{"echoed": input.Message, "tool": "flaky"})
+		return toolOutput(req.RequestId, string(outJSON))
+
+	case "huge_output":
+		if input.KB < 0 || input.KB > maxHugeOutputKB {
+			return toolError(req.RequestId, "INVALID_ARGUMENTS", fmt.Sprintf("kb must be between 0 and %d", maxHugeOutputKB))
+		}
+		return toolOutput(req.RequestId, fmt.Sprintf(`{"kb": %d, "data": "%s"}`, input.KB, strings.Repeat("x", input.KB*1024)))
+
+	default:
+		return toolOutput(req.RequestId, fmt.Sprintf(`{"error": "unknown tool: %s"}`, req.ToolName))
+	}
+}
+
+func toolOutput(requestID, outputJSON string) *pb.ExecuteToolResponse {
+	return &pb.ExecuteToolResponse{
+		RequestId: requestID,
+		Result:    &pb.ExecuteToolResponse_OutputJson{OutputJson: outputJSON},
+	}
+}
+
+// toolError encodes a code and message as JSON in the error result so tests
+// can assert on the code without string matching
+func toolError(requestID, code, message string) *pb.ExecuteToolResponse {
+	errJSON, _ := json.Marshal(map[string]string{"code": code, "message": message})
+	return &pb.ExecuteToolResponse{
+		RequestId: requestID,
+		Result:    &pb.ExecuteToolResponse_Error{Error: string(errJSON)},
+	}
+}
+
+// envInt reads a non-negative integer from the environment, returning 0 when unset
+func envInt(name string) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Fatalf("Invalid %s=%q: must be a non-negative integer", name, v)
 	}
+	return n
 }